@@ -0,0 +1,103 @@
+// Package browser implements an internal/http.Fetcher backed by a headless
+// Chromium instance via chromedp, for watch targets that are SPAs and
+// otherwise return little more than an empty shell to a plain HTTP GET.
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/trhacknon/websitewatcher/internal/config"
+	wwhttp "github.com/trhacknon/websitewatcher/internal/http"
+	"github.com/trhacknon/websitewatcher/internal/logger"
+)
+
+const defaultWaitTimeout = 30 * time.Second
+
+// Fetcher drives a headless Chromium via chromedp and returns the rendered
+// DOM as the body, implementing internal/http.Fetcher.
+type Fetcher struct {
+	defaultTimeout time.Duration
+}
+
+func NewFetcher(defaultTimeout time.Duration) *Fetcher {
+	if defaultTimeout <= 0 {
+		defaultTimeout = defaultWaitTimeout
+	}
+	return &Fetcher{defaultTimeout: defaultTimeout}
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, watch config.Watch, log logger.Logger) (int, http.Header, time.Duration, []byte, error) {
+	timeout := watch.Browser.WaitTimeout.Duration
+	if timeout <= 0 {
+		timeout = f.defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Debug("rendering page in headless browser", "url", watch.URL, "timeout", timeout)
+
+	allocOpts := chromedp.DefaultExecAllocatorOptions[:]
+	if watch.Browser.ViewportWidth > 0 && watch.Browser.ViewportHeight > 0 {
+		allocOpts = append(allocOpts, chromedp.WindowSize(watch.Browser.ViewportWidth, watch.Browser.ViewportHeight))
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, allocOpts...)
+	defer cancelAlloc()
+
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+
+	start := time.Now()
+
+	// chromedp.Navigate never surfaces the response status, so capture the
+	// main frame's status via the CDP Network domain instead. Without this,
+	// a 404/500 SPA shell looks like a perfectly valid 200 response.
+	statusCode := http.StatusOK
+	var mainFrameRequestID network.RequestID
+	chromedp.ListenTarget(browserCtx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			if ev.Type == network.ResourceTypeDocument && mainFrameRequestID == "" {
+				mainFrameRequestID = ev.RequestID
+			}
+		case *network.EventResponseReceived:
+			if ev.RequestID == mainFrameRequestID {
+				statusCode = int(ev.Response.Status)
+			}
+		}
+	})
+
+	actions := []chromedp.Action{network.Enable(), chromedp.Navigate(watch.URL)}
+	if watch.Browser.WaitSelector != "" {
+		actions = append(actions, chromedp.WaitVisible(watch.Browser.WaitSelector, chromedp.ByQuery))
+	} else {
+		actions = append(actions, chromedp.WaitReady("body", chromedp.ByQuery))
+	}
+	if watch.Browser.ExtraJS != "" {
+		actions = append(actions, chromedp.Evaluate(watch.Browser.ExtraJS, nil))
+	}
+
+	var body string
+	actions = append(actions, chromedp.OuterHTML("html", &body, chromedp.ByQuery))
+
+	if err := chromedp.Run(browserCtx, actions...); err != nil {
+		return -1, nil, time.Since(start), nil, fmt.Errorf("could not render %s: %w", watch.URL, err)
+	}
+
+	// match the plain HTTP fetcher's behavior so a 404/500 SPA shell goes
+	// through the same ignore-list/invalid-response handling instead of
+	// being diffed as if it were a normal page.
+	if statusCode != http.StatusOK {
+		return -1, nil, time.Since(start), nil, &wwhttp.InvalidResponseError{
+			StatusCode: statusCode,
+			Body:       []byte(body),
+		}
+	}
+
+	return statusCode, nil, time.Since(start), []byte(body), nil
+}
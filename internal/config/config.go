@@ -0,0 +1,162 @@
+// Package config handles reading and validating the application configuration file.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Duration wraps time.Duration so it can be configured as a human readable
+// string (e.g. "30s") in the JSON config file.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("could not parse duration %q: %w", s, err)
+	}
+	d.Duration = dur
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Duration.String())
+}
+
+type Replace struct {
+	Pattern     string `json:"pattern"`
+	ReplaceWith string `json:"replace_with"`
+}
+
+type Watch struct {
+	Name                         string    `json:"name"`
+	URL                          string    `json:"url"`
+	Disabled                     bool      `json:"disabled"`
+	Pattern                      string    `json:"pattern"`
+	Replaces                     []Replace `json:"replaces"`
+	AdditionalHTTPErrorsToIgnore []int     `json:"additional_http_errors_to_ignore"`
+	// Notifiers lists the notifier names (see Configuration.Notifiers) this
+	// watch should use. If empty, Configuration.DefaultNotifiers is used.
+	Notifiers []string `json:"notifiers"`
+	// Fetcher selects how the watch is retrieved: "http" (default, a plain
+	// GET request) or "browser" (render via a headless Chromium instance,
+	// for JS-rendered pages).
+	Fetcher string         `json:"fetcher"`
+	Browser BrowserOptions `json:"browser"`
+	Diff    DiffOptions    `json:"diff"`
+}
+
+// DiffOptions configures how a watch's change diff is rendered.
+type DiffOptions struct {
+	// Backend selects the diff implementation: "local" (default, in-process)
+	// or "api" to use the legacy DiffAPI remote service.
+	Backend string `json:"backend"`
+	// Formats selects the non-HTML representation generated in addition to
+	// the HTML view always used for mail, for text-only notifiers: "json"
+	// (list of {op, line, text}) if listed, otherwise "text" (unified diff).
+	// Only one representation is generated; listing both yields only json.
+	Formats []string `json:"formats"`
+	// StripHTML strips HTML tags and NormalizeWhitespace collapses repeated
+	// whitespace before diffing, so cosmetic churn doesn't produce noise.
+	StripHTML           bool `json:"strip_html"`
+	NormalizeWhitespace bool `json:"normalize_whitespace"`
+}
+
+// BrowserOptions configures the "browser" fetcher for a single watch.
+type BrowserOptions struct {
+	// WaitSelector is a CSS selector to wait for before reading the DOM. If
+	// empty, the fetcher waits for the body element to be ready instead.
+	WaitSelector   string   `json:"wait_selector"`
+	WaitTimeout    Duration `json:"wait_timeout"`
+	ViewportWidth  int      `json:"viewport_width"`
+	ViewportHeight int      `json:"viewport_height"`
+	// ExtraJS is run after the page (and WaitSelector, if set) is ready,
+	// before the rendered DOM is captured.
+	ExtraJS string `json:"extra_js"`
+}
+
+type MailConfig struct {
+	Server   string `json:"server"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// NtfyConfig configures a push notifier in the style of ntfy.sh: an HTTP
+// POST to a topic URL with Title/Priority/Tags headers.
+type NtfyConfig struct {
+	Name     string `json:"name"`
+	Server   string `json:"server"`
+	Topic    string `json:"topic"`
+	Priority string `json:"priority"`
+	Tags     string `json:"tags"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// WebhookConfig configures a generic JSON webhook notifier. Body is a
+// text/template template so users can shape Slack/Discord/Mattermost
+// payloads.
+type WebhookConfig struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+type Configuration struct {
+	Useragent  string   `json:"useragent"`
+	Retries    int      `json:"retries"`
+	RetryDelay Duration `json:"retry_delay"`
+	// MaxRetryDelay caps the exponential backoff applied between retries.
+	MaxRetryDelay Duration `json:"max_retry_delay"`
+	Timeout       Duration `json:"timeout"`
+	// ErrorRateWindow / ErrorRateThreshold control the rolling error rate
+	// cooldown: once the share of failed requests to a host within the
+	// window reaches the threshold, the host is blocked for HostCooldown.
+	ErrorRateWindow    Duration        `json:"error_rate_window"`
+	ErrorRateThreshold float64         `json:"error_rate_threshold"`
+	HostCooldown       Duration        `json:"host_cooldown"`
+	ParallelChecks     int64           `json:"parallel_checks"`
+	Database           string          `json:"database"`
+	HTTPErrorsToIgnore []int           `json:"http_errors_to_ignore"`
+	Watches            []Watch         `json:"watches"`
+	Mail               MailConfig      `json:"mail"`
+	Ntfy               []NtfyConfig    `json:"ntfy"`
+	Webhooks           []WebhookConfig `json:"webhooks"`
+	// DefaultNotifiers lists the notifier names used for watches that do not
+	// set their own Notifiers.
+	DefaultNotifiers []string `json:"default_notifiers"`
+	// Debug sets the initial (and SIGHUP-reloaded) log level to debug. The
+	// -debug CLI flag takes precedence if set.
+	Debug bool `json:"debug"`
+}
+
+func GetConfig(filename string) (*Configuration, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("no config file supplied")
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file %s: %w", filename, err)
+	}
+
+	var c Configuration
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", filename, err)
+	}
+
+	return &c, nil
+}
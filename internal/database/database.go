@@ -0,0 +1,100 @@
+// Package database persists the last seen content of every watch between runs.
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+	"github.com/trhacknon/websitewatcher/internal/logger"
+)
+
+type entry struct {
+	Content []byte `json:"content"`
+}
+
+type Database struct {
+	mu sync.Mutex
+
+	LastRun int64            `json:"last_run"`
+	Entries map[string]entry `json:"entries"`
+}
+
+func ReadDatabase(filename string) (*Database, error) {
+	db := &Database{Entries: map[string]entry{}}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return db, nil
+		}
+		return nil, fmt.Errorf("could not read database file %s: %w", filename, err)
+	}
+
+	if err := json.Unmarshal(b, db); err != nil {
+		return nil, fmt.Errorf("could not parse database file %s: %w", filename, err)
+	}
+
+	return db, nil
+}
+
+func (db *Database) SaveDatabase(filename string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	b, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal database: %w", err)
+	}
+
+	if err := os.WriteFile(filename, b, 0o600); err != nil {
+		return fmt.Errorf("could not write database file %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+func (db *Database) GetDatabaseEntry(url string) []byte {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	e, ok := db.Entries[url]
+	if !ok {
+		return nil
+	}
+	return e.Content
+}
+
+func (db *Database) SetDatabaseEntry(url string, content []byte) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Entries[url] = entry{Content: content}
+}
+
+func (db *Database) SetLastRun(ts int64) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.LastRun = ts
+}
+
+// CleanupDatabase removes entries for watches that are no longer configured.
+func (db *Database) CleanupDatabase(log logger.Logger, c config.Configuration) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	known := map[string]bool{}
+	for _, w := range c.Watches {
+		known[w.URL] = true
+	}
+
+	for url := range db.Entries {
+		if !known[url] {
+			log.Info("removing stale database entry", "url", url)
+			delete(db.Entries, url)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package diff renders a human readable diff between two versions of a
+// watched page for inclusion in change notifications.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	ihttp "github.com/trhacknon/websitewatcher/internal/http"
+)
+
+type diffAPIRequest struct {
+	Text1 string `json:"text1"`
+	Text2 string `json:"text2"`
+}
+
+type diffAPIResponse struct {
+	CSS  string `json:"css"`
+	HTML string `json:"html"`
+}
+
+// DiffAPI renders the diff between text1 and text2 using a remote diffing
+// service and returns the css and html needed to display it inline in an
+// email. It is kept as an optional backend (config.DiffOptions.Backend =
+// "api") for backwards compatibility; DiffHTML is the local default.
+func DiffAPI(client *ihttp.HTTPClient, text1, text2 string) (string, string, error) {
+	body, err := json.Marshal(diffAPIRequest{Text1: text1, Text2: text2})
+	if err != nil {
+		return "", "", fmt.Errorf("could not marshal diff request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.diffchecker.com/public/text", bytes.NewReader(body))
+	if err != nil {
+		return "", "", fmt.Errorf("could not create diff request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("could not call diff api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("could not read diff api response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("diff api returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var parsed diffAPIResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", "", fmt.Errorf("could not parse diff api response: %w", err)
+	}
+
+	return parsed.CSS, parsed.HTML, nil
+}
@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Op identifies the kind of change a JSONDiffEntry represents.
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// JSONDiffEntry is one line of the compact JSON diff form, suitable for
+// webhook payloads.
+type JSONDiffEntry struct {
+	Op   Op     `json:"op"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+var (
+	htmlTagRe    = regexp.MustCompile(`<[^>]*>`)
+	whitespaceRe = regexp.MustCompile(`[ \t]+`)
+)
+
+// Prepare normalizes text before diffing so cosmetic churn (timestamps,
+// CSRF tokens, ...) doesn't produce a noisy diff: optionally strips HTML
+// tags and collapses runs of whitespace.
+func Prepare(text string, stripHTML, normalizeWhitespace bool) string {
+	if stripHTML {
+		text = htmlTagRe.ReplaceAllString(text, "")
+	}
+	if normalizeWhitespace {
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = whitespaceRe.ReplaceAllString(strings.TrimSpace(line), " ")
+		}
+		text = strings.Join(lines, "\n")
+	}
+	return text
+}
+
+const diffCSS = `.diff-table { border-collapse: collapse; width: 100%; font-family: monospace; white-space: pre-wrap; } .diff-table td { vertical-align: top; padding: 0 4px; width: 50%; } .diff-insert { background-color: #d4fcbc; } .diff-delete { background-color: #fbb6c2; text-decoration: line-through; }`
+
+// diffLines runs a line-granularity diff and strips the zero-length chunks
+// DiffCleanupSemantic leaves behind around real changes, so renderers don't
+// have to special-case empty Diff.Text themselves.
+func diffLines(text1, text2 string) []diffmatchpatch.Diff {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(text1, text2)
+	diffs := dmp.DiffMain(a, b, false)
+	diffs = dmp.DiffCharsToLines(diffs, lines)
+	diffs = dmp.DiffCleanupSemantic(diffs)
+
+	cleaned := diffs[:0]
+	for _, d := range diffs {
+		if d.Text == "" {
+			continue
+		}
+		cleaned = append(cleaned, d)
+	}
+	return cleaned
+}
+
+// splitDiffLines splits a diff chunk's text into its individual lines,
+// dropping the trailing empty element left by a final "\n".
+func splitDiffLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// DiffHTML renders an inline, side-by-side (old-vs-new, two column) diff
+// view with add/remove cells and its own css. It is a local, in-process
+// drop-in replacement for DiffAPI's (css, html, err) return.
+func DiffHTML(text1, text2 string) (string, string, error) {
+	var sb strings.Builder
+	sb.WriteString(`<table class="diff-table">`)
+	for _, d := range diffLines(text1, text2) {
+		for _, line := range splitDiffLines(d.Text) {
+			escaped := html.EscapeString(line)
+			switch d.Type {
+			case diffmatchpatch.DiffInsert:
+				fmt.Fprintf(&sb, `<tr><td></td><td class="diff-insert">%s</td></tr>`, escaped)
+			case diffmatchpatch.DiffDelete:
+				fmt.Fprintf(&sb, `<tr><td class="diff-delete">%s</td><td></td></tr>`, escaped)
+			case diffmatchpatch.DiffEqual:
+				fmt.Fprintf(&sb, `<tr><td>%s</td><td>%s</td></tr>`, escaped, escaped)
+			}
+		}
+	}
+	sb.WriteString(`</table>`)
+	return diffCSS, sb.String(), nil
+}
+
+// DiffText renders a unified-diff style plain text form, for text-only
+// notifiers such as ntfy.
+func DiffText(text1, text2 string) string {
+	var sb strings.Builder
+	for _, d := range diffLines(text1, text2) {
+		prefix := "  "
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			prefix = "+ "
+		case diffmatchpatch.DiffDelete:
+			prefix = "- "
+		}
+		for _, line := range splitDiffLines(d.Text) {
+			sb.WriteString(prefix)
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// DiffJSON renders the compact JSON diff form: a list of {op, line, text}.
+func DiffJSON(text1, text2 string) ([]byte, error) {
+	var entries []JSONDiffEntry
+	line := 1
+	for _, d := range diffLines(text1, text2) {
+		op := OpEqual
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = OpInsert
+		case diffmatchpatch.DiffDelete:
+			op = OpDelete
+		}
+
+		for _, text := range splitDiffLines(d.Text) {
+			entries = append(entries, JSONDiffEntry{Op: op, Line: line, Text: text})
+			if d.Type != diffmatchpatch.DiffDelete {
+				line++
+			}
+		}
+	}
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal diff json: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,105 @@
+package diff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		name                string
+		text                string
+		stripHTML           bool
+		normalizeWhitespace bool
+		want                string
+	}{
+		{
+			name: "no options leaves text untouched",
+			text: "<b>hello</b>  world\t!",
+			want: "<b>hello</b>  world\t!",
+		},
+		{
+			name:      "strips html tags",
+			text:      "<div>hello <b>world</b></div>",
+			stripHTML: true,
+			want:      "hello world",
+		},
+		{
+			name:                "normalizes whitespace per line",
+			text:                "  hello   world  \nfoo\t\tbar",
+			normalizeWhitespace: true,
+			want:                "hello world\nfoo bar",
+		},
+		{
+			name:                "strips html and normalizes whitespace together",
+			text:                "<p>hello   </p>\n<p>world</p>",
+			stripHTML:           true,
+			normalizeWhitespace: true,
+			want:                "hello\nworld",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Prepare(tt.text, tt.stripHTML, tt.normalizeWhitespace)
+			if got != tt.want {
+				t.Errorf("Prepare(%q, %v, %v) = %q, want %q", tt.text, tt.stripHTML, tt.normalizeWhitespace, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffHTML(t *testing.T) {
+	_, html, err := DiffHTML("line1\nline2\n", "line1\nline3\n")
+	if err != nil {
+		t.Fatalf("DiffHTML returned error: %v", err)
+	}
+
+	if !strings.HasPrefix(html, `<table class="diff-table">`) {
+		t.Errorf("DiffHTML output does not start with a side-by-side table: %q", html)
+	}
+	if !strings.Contains(html, `<tr><td class="diff-delete">line2</td><td></td></tr>`) {
+		t.Errorf("DiffHTML did not render the removed line in the left column: %q", html)
+	}
+	if !strings.Contains(html, `<tr><td></td><td class="diff-insert">line3</td></tr>`) {
+		t.Errorf("DiffHTML did not render the added line in the right column: %q", html)
+	}
+	if !strings.Contains(html, `<tr><td>line1</td><td>line1</td></tr>`) {
+		t.Errorf("DiffHTML did not render the unchanged line in both columns: %q", html)
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	got := DiffText("line1\nline2\n", "line1\nline3\n")
+	want := "  line1\n- line2\n+ line3\n"
+	if got != want {
+		t.Errorf("DiffText() = %q, want %q", got, want)
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	b, err := DiffJSON("line1\nline2\n", "line1\nline3\n")
+	if err != nil {
+		t.Fatalf("DiffJSON returned error: %v", err)
+	}
+
+	var entries []JSONDiffEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatalf("could not unmarshal DiffJSON output: %v", err)
+	}
+
+	want := []JSONDiffEntry{
+		{Op: OpEqual, Line: 1, Text: "line1"},
+		{Op: OpDelete, Line: 2, Text: "line2"},
+		{Op: OpInsert, Line: 2, Text: "line3"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("DiffJSON() returned %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
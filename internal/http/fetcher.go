@@ -0,0 +1,24 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+	"github.com/trhacknon/websitewatcher/internal/logger"
+)
+
+// Fetcher retrieves the content of a watch. HTTPClient is the default
+// implementation; other implementations (e.g. a headless browser, see
+// internal/browser) can be swapped in per watch via config.Watch.Fetcher.
+// log is expected to already carry the watch's context (name, url).
+type Fetcher interface {
+	Fetch(ctx context.Context, watch config.Watch, log logger.Logger) (statusCode int, headers http.Header, duration time.Duration, body []byte, err error)
+}
+
+// Fetch implements Fetcher on top of GetRequest.
+func (c *HTTPClient) Fetch(ctx context.Context, watch config.Watch, log logger.Logger) (int, http.Header, time.Duration, []byte, error) {
+	statusCode, headers, duration, body, err := c.GetRequest(ctx, watch.URL, log)
+	return statusCode, headers, duration, body, err
+}
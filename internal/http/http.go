@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/trhacknon/websitewatcher/internal/logger"
@@ -17,12 +22,36 @@ type HTTPClient struct {
 	retries    int
 	retryDelay time.Duration
 	client     *http.Client
-	logger     logger.Logger
+
+	// maxRetryDelay caps the exponential backoff applied between retries.
+	maxRetryDelay time.Duration
+	// errorRateWindow / errorRateThreshold control the rolling error rate
+	// cooldown: once the share of failed requests to a host within the
+	// window reaches the threshold, the host is blocked for hostCooldown.
+	errorRateWindow    time.Duration
+	errorRateThreshold float64
+	hostCooldown       time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState tracks per-host quota blocks and recent request outcomes, used
+// to back off from hosts that are rate limiting or erroring a lot instead
+// of hammering them on every scheduled run.
+type hostState struct {
+	blockedUntil time.Time
+	attempts     []attempt
+}
+
+type attempt struct {
+	at     time.Time
+	failed bool
 }
 
 type InvalidResponseError struct {
 	StatusCode int
-	Header     map[string][]string
+	Header     http.Header
 	Body       []byte
 }
 
@@ -30,7 +59,19 @@ func (err *InvalidResponseError) Error() string {
 	return fmt.Sprintf("got invalid response on http request: status: %d, bodylen: %d", err.StatusCode, len(err.Body))
 }
 
-func NewHTTPClient(userAgent string, retries int, retryDelay time.Duration, timeout time.Duration, logger logger.Logger) *HTTPClient {
+// QuotaExceededError is returned instead of issuing a request when a host is
+// currently blocked, either because it asked us to back off via Retry-After
+// or because its rolling error rate tripped the cooldown threshold.
+type QuotaExceededError struct {
+	Host       string
+	RetryAfter time.Time
+}
+
+func (err *QuotaExceededError) Error() string {
+	return fmt.Sprintf("host %s is in quota cooldown until %s", err.Host, err.RetryAfter.Format(time.RFC3339))
+}
+
+func NewHTTPClient(userAgent string, retries int, retryDelay, maxRetryDelay, timeout, errorRateWindow time.Duration, errorRateThreshold float64, hostCooldown time.Duration) *HTTPClient {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
@@ -39,11 +80,15 @@ func NewHTTPClient(userAgent string, retries int, retryDelay time.Duration, time
 		Transport: tr,
 	}
 	return &HTTPClient{
-		userAgent:  userAgent,
-		retries:    retries,
-		retryDelay: retryDelay,
-		client:     &httpClient,
-		logger:     logger,
+		userAgent:          userAgent,
+		retries:            retries,
+		retryDelay:         retryDelay,
+		maxRetryDelay:      maxRetryDelay,
+		errorRateWindow:    errorRateWindow,
+		errorRateThreshold: errorRateThreshold,
+		hostCooldown:       hostCooldown,
+		client:             &httpClient,
+		hosts:              map[string]*hostState{},
 	}
 }
 
@@ -52,7 +97,140 @@ func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 	return c.client.Do(req)
 }
 
-func (c *HTTPClient) fetchURL(ctx context.Context, url string) (int, map[string][]string, time.Duration, []byte, error) {
+// BlockedHosts returns the hosts currently blocked due to a quota or error
+// rate cooldown, keyed by the time they'll become available again. main.go
+// uses this to skip watches and avoid spamming error emails while a host is
+// blocked.
+func (c *HTTPClient) BlockedHosts() map[string]time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	blocked := make(map[string]time.Time)
+	for host, s := range c.hosts {
+		if s.blockedUntil.After(now) {
+			blocked[host] = s.blockedUntil
+		}
+	}
+	return blocked
+}
+
+// hostStateLocked returns the state for host, creating it if needed. Callers
+// must hold c.mu.
+func (c *HTTPClient) hostStateLocked(host string) *hostState {
+	s, ok := c.hosts[host]
+	if !ok {
+		s = &hostState{}
+		c.hosts[host] = s
+	}
+	return s
+}
+
+func (c *HTTPClient) checkBlocked(host string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.hosts[host]
+	if !ok || !s.blockedUntil.After(time.Now()) {
+		return nil
+	}
+	return &QuotaExceededError{Host: host, RetryAfter: s.blockedUntil}
+}
+
+func (c *HTTPClient) blockHost(host string, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.hostStateLocked(host)
+	if until.After(s.blockedUntil) {
+		s.blockedUntil = until
+	}
+}
+
+// minErrorRateSamples is the number of recent attempts required within
+// errorRateWindow before the error rate cooldown can trigger, so a single
+// failing host isn't blocked off one data point.
+const minErrorRateSamples = 5
+
+// recordResult tracks the outcome of a request to host and blocks the host
+// for hostCooldown once its rolling error rate reaches errorRateThreshold.
+func (c *HTTPClient) recordResult(host string, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.hostStateLocked(host)
+	now := time.Now()
+	s.attempts = append(s.attempts, attempt{at: now, failed: failed})
+
+	cutoff := now.Add(-c.errorRateWindow)
+	kept := s.attempts[:0]
+	var total, errs int
+	for _, a := range s.attempts {
+		if a.at.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, a)
+		total++
+		if a.failed {
+			errs++
+		}
+	}
+	s.attempts = kept
+
+	if total >= minErrorRateSamples && c.errorRateThreshold > 0 && float64(errs)/float64(total) >= c.errorRateThreshold {
+		s.blockedUntil = now.Add(c.hostCooldown)
+	}
+}
+
+// backoffDelay returns the delay to wait before retry number attempt,
+// applying exponential backoff with jitter capped at maxRetryDelay.
+func (c *HTTPClient) backoffDelay(attempt int) time.Duration {
+	if c.retryDelay <= 0 {
+		return 0
+	}
+
+	delay := c.retryDelay * time.Duration(int64(1)<<uint(attempt-1))
+	if c.maxRetryDelay > 0 && delay > c.maxRetryDelay {
+		delay = c.maxRetryDelay
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay) + 1)) //nolint:gosec // jitter does not need to be cryptographically secure
+	return delay/2 + jitter/2
+}
+
+// parseRetryAfter parses a Retry-After header in either its seconds or
+// HTTP-date form, relative to now.
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		return now.Add(time.Duration(secs) * time.Second), true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+func isSoftError(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+
+	if bytes.Contains(body, []byte("504 - Gateway Time-out")) ||
+		bytes.Contains(body, []byte("404 - Not Found")) ||
+		bytes.Contains(body, []byte("503 - Service Unavailable")) {
+		return true
+	}
+
+	return false
+}
+
+func (c *HTTPClient) fetchURL(ctx context.Context, url string) (int, http.Header, time.Duration, []byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return -1, nil, -1, nil, fmt.Errorf("could create get request for %s: %w", url, err)
@@ -72,7 +250,6 @@ func (c *HTTPClient) fetchURL(ctx context.Context, url string) (int, map[string]
 	}
 
 	if resp.StatusCode != 200 || len(body) == 0 || isSoftError(body) {
-
 		return -1, nil, duration, nil, &InvalidResponseError{
 			StatusCode: resp.StatusCode,
 			Header:     resp.Header,
@@ -83,50 +260,59 @@ func (c *HTTPClient) fetchURL(ctx context.Context, url string) (int, map[string]
 	return resp.StatusCode, resp.Header, duration, body, nil
 }
 
-func isSoftError(body []byte) bool {
-	if len(body) == 0 {
-		return false
+func (c *HTTPClient) GetRequest(ctx context.Context, rawURL string, log logger.Logger) (int, map[string][]string, time.Duration, []byte, error) {
+	host, err := hostOf(rawURL)
+	if err != nil {
+		return -1, nil, -1, nil, err
 	}
 
-	if bytes.Contains(body, []byte("504 - Gateway Time-out")) ||
-		bytes.Contains(body, []byte("404 - Not Found")) ||
-		bytes.Contains(body, []byte("503 - Service Unavailable")) {
-		return true
+	if err := c.checkBlocked(host); err != nil {
+		return -1, nil, -1, nil, err
 	}
 
-	return false
-}
-
-func (c *HTTPClient) GetRequest(ctx context.Context, url string) (int, map[string][]string, time.Duration, []byte, error) {
 	var statusCode int
 	var requestDuration time.Duration
 	var body []byte
-	var header map[string][]string
-	var err error
+	var header http.Header
 	// check with retries
 	for i := 1; i <= c.retries; i++ {
-		c.logger.Debugf("try #%d for %s", i, url)
-		statusCode, header, requestDuration, body, err = c.fetchURL(ctx, url)
+		log.Debug("fetching url", "attempt", i, "url", rawURL)
+		statusCode, header, requestDuration, body, err = c.fetchURL(ctx, rawURL)
 		if err == nil {
-			// break out on success
+			c.recordResult(host, false)
 			break
 		}
 
+		var invalidErr *InvalidResponseError
+		if errors.As(err, &invalidErr) && (invalidErr.StatusCode == http.StatusTooManyRequests || invalidErr.StatusCode == http.StatusServiceUnavailable) {
+			c.recordResult(host, true)
+
+			until, ok := parseRetryAfter(invalidErr.Header.Get("Retry-After"), time.Now())
+			if !ok {
+				until = time.Now().Add(c.hostCooldown)
+			}
+			c.blockHost(host, until)
+			return -1, nil, -1, nil, &QuotaExceededError{Host: host, RetryAfter: until}
+		}
+
+		c.recordResult(host, true)
+
 		// if we reach here, we have an error, retry
 		if i == c.retries {
 			// break out to not print the rety message on the last try
 			break
 		}
 
-		if c.retryDelay > 0 {
-			c.logger.Error(fmt.Errorf("got error on try #%d for %s, retrying after %s: %w", i, url, c.retryDelay, err))
+		delay := c.backoffDelay(i)
+		if delay > 0 {
+			log.Error("request failed, retrying after delay", "attempt", i, "url", rawURL, "delay", delay, "error", err)
 			select {
 			case <-ctx.Done():
 				return -1, nil, -1, nil, ctx.Err()
-			case <-time.After(c.retryDelay):
+			case <-time.After(delay):
 			}
 		} else {
-			c.logger.Error(fmt.Errorf("got error on try #%d for %s, retrying: %w", i, url, err))
+			log.Error("request failed, retrying", "attempt", i, "url", rawURL, "error", err)
 		}
 	}
 
@@ -137,3 +323,11 @@ func (c *HTTPClient) GetRequest(ctx context.Context, url string) (int, map[strin
 
 	return statusCode, header, requestDuration, body, nil
 }
+
+func hostOf(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url %s: %w", rawURL, err)
+	}
+	return u.Host, nil
+}
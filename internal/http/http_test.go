@@ -0,0 +1,106 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{
+			name:  "empty value",
+			value: "",
+			ok:    false,
+		},
+		{
+			name:  "seconds form",
+			value: "120",
+			want:  now.Add(120 * time.Second),
+			ok:    true,
+		},
+		{
+			name:  "http-date form",
+			value: "Mon, 01 Jan 2024 12:05:00 GMT",
+			want:  time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC),
+			ok:    true,
+		},
+		{
+			name:  "garbage value",
+			value: "not-a-value",
+			ok:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.ok {
+				t.Fatalf("parseRetryAfter(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	t.Run("zero retryDelay disables backoff", func(t *testing.T) {
+		c := &HTTPClient{retryDelay: 0}
+		if got := c.backoffDelay(1); got != 0 {
+			t.Errorf("backoffDelay(1) = %v, want 0", got)
+		}
+	})
+
+	t.Run("grows exponentially and stays within jittered bounds", func(t *testing.T) {
+		c := &HTTPClient{retryDelay: time.Second}
+		for attempt := 1; attempt <= 5; attempt++ {
+			base := time.Second * time.Duration(int64(1)<<uint(attempt-1))
+			got := c.backoffDelay(attempt)
+			if got < base/2 || got > base {
+				t.Errorf("backoffDelay(%d) = %v, want in [%v, %v]", attempt, got, base/2, base)
+			}
+		}
+	})
+
+	t.Run("caps at maxRetryDelay", func(t *testing.T) {
+		c := &HTTPClient{retryDelay: time.Second, maxRetryDelay: 2 * time.Second}
+		for attempt := 1; attempt <= 10; attempt++ {
+			if got := c.backoffDelay(attempt); got > 2*time.Second {
+				t.Errorf("backoffDelay(%d) = %v, want <= max %v", attempt, got, 2*time.Second)
+			}
+		}
+	})
+}
+
+func TestHostOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawURL  string
+		want    string
+		wantErr bool
+	}{
+		{name: "simple url", rawURL: "https://example.com/path", want: "example.com"},
+		{name: "url with port", rawURL: "https://example.com:8443/path", want: "example.com:8443"},
+		{name: "invalid url", rawURL: "://bad-url", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hostOf(tt.rawURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("hostOf(%q) error = %v, wantErr %v", tt.rawURL, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("hostOf(%q) = %q, want %q", tt.rawURL, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Package logger defines the structured logging interface used throughout
+// the application, backed by log/slog. Unlike the old logrus Printf-style
+// methods, every call takes key/value pairs so a line can be filtered by
+// e.g. watch name instead of grepping a formatted message.
+package logger
+
+import (
+	"log/slog"
+	"os"
+)
+
+// Logger is a structured key/value logger. New returns one backed by
+// *slog.Logger; *slog.Logger itself does not satisfy it, because its With
+// returns *slog.Logger rather than Logger.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+	// With returns a child Logger that always includes the given key/value
+	// pairs, used to scope a logger to a single watch.
+	With(args ...any) Logger
+}
+
+// LevelVar is a hot-swappable log level: changing it affects every Logger
+// derived from the same base, including already created watch loggers.
+type LevelVar = slog.LevelVar
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// New returns a Logger writing structured text to stdout at the level held
+// by level. level can be mutated at any time (see watchSIGHUP in package
+// main) to change verbosity without restarting.
+func New(level *LevelVar) Logger {
+	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	return &slogLogger{l: slog.New(handler)}
+}
+
+func (s *slogLogger) Debug(msg string, args ...any) { s.l.Debug(msg, args...) }
+func (s *slogLogger) Info(msg string, args ...any)  { s.l.Info(msg, args...) }
+func (s *slogLogger) Error(msg string, args ...any) { s.l.Error(msg, args...) }
+
+func (s *slogLogger) With(args ...any) Logger {
+	return &slogLogger{l: s.l.With(args...)}
+}
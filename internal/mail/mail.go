@@ -0,0 +1,59 @@
+// Package mail sends change and error notifications via SMTP.
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+	"gopkg.in/mail.v2"
+)
+
+// Mail implements notify.Notifier on top of SMTP.
+type Mail struct {
+	config *config.Configuration
+}
+
+func NewMail(c *config.Configuration) *Mail {
+	return &Mail{config: c}
+}
+
+func (m *Mail) Name() string {
+	return "mail"
+}
+
+func (m *Mail) dialer() *mail.Dialer {
+	return mail.NewDialer(m.config.Mail.Server, m.config.Mail.Port, m.config.Mail.User, m.config.Mail.Password)
+}
+
+func (m *Mail) SendHTMLEmail(watch config.Watch, subject, htmlContent string) error {
+	msg := mail.NewMessage()
+	msg.SetHeader("From", m.config.Mail.From)
+	msg.SetHeader("To", m.config.Mail.To)
+	msg.SetHeader("Subject", subject)
+	msg.SetBody("text/html", htmlContent)
+
+	if err := m.dialer().DialAndSend(msg); err != nil {
+		return fmt.Errorf("could not send mail for %s: %w", watch.Name, err)
+	}
+	return nil
+}
+
+func (m *Mail) SendErrorEmail(watch config.Watch, watchErr error) error {
+	subject := fmt.Sprintf("Error on %s", watch.Name)
+	body := fmt.Sprintf("<html><body>Error on %s (%s): %s</body></html>", watch.Name, watch.URL, watchErr)
+	return m.SendHTMLEmail(watch, subject, body)
+}
+
+// NotifyChange implements notify.Notifier. diff is ignored: htmlBody is a
+// complete HTML document that already embeds the rendered diff, and
+// appending the plain-text/JSON form after it would just duplicate the
+// diff inside malformed markup.
+func (m *Mail) NotifyChange(_ context.Context, watch config.Watch, subject, _, htmlBody, _ string) error {
+	return m.SendHTMLEmail(watch, subject, htmlBody)
+}
+
+// NotifyError implements notify.Notifier.
+func (m *Mail) NotifyError(_ context.Context, watch config.Watch, watchErr error) error {
+	return m.SendErrorEmail(watch, watchErr)
+}
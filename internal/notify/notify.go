@@ -0,0 +1,60 @@
+// Package notify defines the Notifier interface implemented by every
+// notification backend (email, push, webhook, ...) and lets a single
+// change fan out to several of them at once.
+package notify
+
+import (
+	"context"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+)
+
+// Notifier is implemented by every notification backend.
+type Notifier interface {
+	// Name returns the notifier name as referenced from the config
+	// (Watch.Notifiers / Configuration.DefaultNotifiers).
+	Name() string
+	// NotifyChange is called when the watched content changed. diff is the
+	// plain rendered diff between the old and new content (unified text, or
+	// JSON if the watch selects it), the same for every notifier; htmlBody
+	// already embeds the HTML view. diff is empty when there is none.
+	NotifyChange(ctx context.Context, watch config.Watch, subject, textBody, htmlBody, diff string) error
+	// NotifyError is called when checking a watch failed.
+	NotifyError(ctx context.Context, watch config.Watch, watchErr error) error
+}
+
+// Notifiers is a named set of Notifier instances, keyed by Notifier.Name().
+type Notifiers map[string]Notifier
+
+// For returns the notifiers a watch should use: its own Watch.Notifiers if
+// set, otherwise defaultNames. If neither is set, it falls back to "mail" so
+// existing configs that predate this field keep notifying the way they
+// always did. An unknown name makes For return an error and no notifiers,
+// so the caller can log a misconfiguration instead of silently dropping it.
+func (n Notifiers) For(watch config.Watch, defaultNames []string) ([]Notifier, error) {
+	names := watch.Notifiers
+	if len(names) == 0 {
+		names = defaultNames
+	}
+	if len(names) == 0 {
+		names = []string{"mail"}
+	}
+
+	notifiers := make([]Notifier, 0, len(names))
+	for _, name := range names {
+		notifier, ok := n[name]
+		if !ok {
+			return nil, unknownNotifierError{name: name}
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers, nil
+}
+
+type unknownNotifierError struct {
+	name string
+}
+
+func (e unknownNotifierError) Error() string {
+	return "unknown notifier: " + e.name
+}
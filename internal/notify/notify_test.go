@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+)
+
+type fakeNotifier struct{ name string }
+
+func (f fakeNotifier) Name() string { return f.name }
+func (f fakeNotifier) NotifyChange(context.Context, config.Watch, string, string, string, string) error {
+	return nil
+}
+func (f fakeNotifier) NotifyError(context.Context, config.Watch, error) error { return nil }
+
+func TestNotifiersFor(t *testing.T) {
+	notifiers := Notifiers{
+		"mail":    fakeNotifier{name: "mail"},
+		"ntfy":    fakeNotifier{name: "ntfy"},
+		"webhook": fakeNotifier{name: "webhook"},
+	}
+
+	tests := []struct {
+		name         string
+		watchNames   []string
+		defaultNames []string
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name: "falls back to mail when watch and defaults are both unset",
+			want: []string{"mail"},
+		},
+		{
+			name:         "uses the global default list when the watch sets none",
+			defaultNames: []string{"ntfy", "webhook"},
+			want:         []string{"ntfy", "webhook"},
+		},
+		{
+			name:         "watch list takes precedence over the global default",
+			watchNames:   []string{"webhook"},
+			defaultNames: []string{"ntfy"},
+			want:         []string{"webhook"},
+		},
+		{
+			name:       "unknown notifier name errors instead of being dropped",
+			watchNames: []string{"does-not-exist"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watch := config.Watch{Notifiers: tt.watchNames}
+			got, err := notifiers.For(watch, tt.defaultNames)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("For() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var names []string
+			for _, n := range got {
+				names = append(names, n.Name())
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("For() = %v, want %v", names, tt.want)
+			}
+			for i := range tt.want {
+				if names[i] != tt.want[i] {
+					t.Errorf("For()[%d] = %q, want %q", i, names[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
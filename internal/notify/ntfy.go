@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+)
+
+// Ntfy sends push notifications in the style of ntfy.sh: an HTTP POST to a
+// topic URL with Title/Priority/Tags headers.
+type Ntfy struct {
+	name     string
+	server   string
+	topic    string
+	priority string
+	tags     string
+	user     string
+	password string
+	client   *http.Client
+}
+
+func NewNtfy(c config.NtfyConfig) *Ntfy {
+	server := c.Server
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	return &Ntfy{
+		name:     c.Name,
+		server:   strings.TrimSuffix(server, "/"),
+		topic:    c.Topic,
+		priority: c.Priority,
+		tags:     c.Tags,
+		user:     c.User,
+		password: c.Password,
+		client:   &http.Client{},
+	}
+}
+
+func (n *Ntfy) Name() string {
+	return n.name
+}
+
+func (n *Ntfy) publish(ctx context.Context, title, message string) error {
+	url := fmt.Sprintf("%s/%s", n.server, n.topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(message))
+	if err != nil {
+		return fmt.Errorf("could not create ntfy request: %w", err)
+	}
+
+	req.Header.Set("Title", title)
+	if n.priority != "" {
+		req.Header.Set("Priority", n.priority)
+	}
+	if n.tags != "" {
+		req.Header.Set("Tags", n.tags)
+	}
+	if n.user != "" {
+		req.SetBasicAuth(n.user, n.password)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not publish to ntfy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ntfy returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *Ntfy) NotifyChange(ctx context.Context, watch config.Watch, subject, textBody, _, diff string) error {
+	title := fmt.Sprintf("Detected change on %s", watch.Name)
+	if subject != "" {
+		title = subject
+	}
+	message := textBody
+	if diff != "" {
+		message += "\n\n" + diff
+	}
+	return n.publish(ctx, title, message)
+}
+
+func (n *Ntfy) NotifyError(ctx context.Context, watch config.Watch, watchErr error) error {
+	return n.publish(ctx, fmt.Sprintf("Error on %s", watch.Name), watchErr.Error())
+}
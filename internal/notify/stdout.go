@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+)
+
+// Stdout writes notifications to a writer instead of sending them. It is
+// used as the implicit notifier in testMode so a dry run is still visible.
+type Stdout struct {
+	out io.Writer
+}
+
+func NewStdout(out io.Writer) *Stdout {
+	return &Stdout{out: out}
+}
+
+func (s *Stdout) Name() string {
+	return "stdout"
+}
+
+func (s *Stdout) NotifyChange(_ context.Context, watch config.Watch, subject, textBody, _, diff string) error {
+	fmt.Fprintf(s.out, "[%s] %s\n%s\n", watch.Name, subject, textBody)
+	if diff != "" {
+		fmt.Fprintf(s.out, "--- diff ---\n%s\n", diff)
+	}
+	return nil
+}
+
+func (s *Stdout) NotifyError(_ context.Context, watch config.Watch, watchErr error) error {
+	fmt.Fprintf(s.out, "[%s] error: %v\n", watch.Name, watchErr)
+	return nil
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+)
+
+// Webhook sends a JSON (or arbitrary templated) payload to a configurable
+// URL, letting users shape the body for Slack/Discord/Mattermost/etc via a
+// text/template.
+type Webhook struct {
+	name    string
+	url     string
+	method  string
+	headers map[string]string
+	body    *template.Template
+	client  *http.Client
+}
+
+// webhookPayload is the data made available to the body template.
+type webhookPayload struct {
+	Watch   config.Watch
+	Subject string
+	Text    string
+	HTML    string
+	Diff    string
+	Error   string
+}
+
+func NewWebhook(c config.WebhookConfig) (*Webhook, error) {
+	method := c.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	body := c.Body
+	if body == "" {
+		body = `{"text":{{.Text | printf "%q"}}}`
+	}
+
+	tmpl, err := template.New(c.Name).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse webhook template for %s: %w", c.Name, err)
+	}
+
+	return &Webhook{
+		name:    c.Name,
+		url:     c.URL,
+		method:  method,
+		headers: c.Headers,
+		body:    tmpl,
+		client:  &http.Client{},
+	}, nil
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+func (w *Webhook) send(ctx context.Context, payload webhookPayload) error {
+	var buf bytes.Buffer
+	if err := w.body.Execute(&buf, payload); err != nil {
+		return fmt.Errorf("could not render webhook body for %s: %w", w.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("could not create webhook request for %s: %w", w.name, err)
+	}
+
+	if _, ok := w.headers["Content-Type"]; !ok {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not call webhook %s: %w", w.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook %s returned status %d: %s", w.name, resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func (w *Webhook) NotifyChange(ctx context.Context, watch config.Watch, subject, textBody, htmlBody, diff string) error {
+	return w.send(ctx, webhookPayload{Watch: watch, Subject: subject, Text: textBody, HTML: htmlBody, Diff: diff})
+}
+
+func (w *Webhook) NotifyError(ctx context.Context, watch config.Watch, watchErr error) error {
+	return w.send(ctx, webhookPayload{Watch: watch, Subject: fmt.Sprintf("Error on %s", watch.Name), Error: watchErr.Error()})
+}
@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+)
+
+func TestWebhookDefaultBody(t *testing.T) {
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody = make([]byte, r.ContentLength)
+		if _, err := r.Body.Read(gotBody); err != nil && err.Error() != "EOF" {
+			t.Errorf("could not read request body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(config.WebhookConfig{Name: "test", URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewWebhook returned error: %v", err)
+	}
+
+	if err := w.NotifyChange(context.Background(), config.Watch{Name: "watch"}, "subject", "changed!", "<html></html>", "diff"); err != nil {
+		t.Fatalf("NotifyChange returned error: %v", err)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("default webhook body is not valid json: %v, body: %s", err, gotBody)
+	}
+	if payload["text"] != "changed!" {
+		t.Errorf("default webhook body text = %q, want %q", payload["text"], "changed!")
+	}
+}
+
+func TestWebhookCustomTemplate(t *testing.T) {
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody = make([]byte, r.ContentLength)
+		r.Body.Read(gotBody) //nolint:errcheck // test server, best effort read
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(config.WebhookConfig{
+		Name: "slack",
+		URL:  srv.URL,
+		Body: `{"channel":{{.Watch.Name | printf "%q"}},"text":{{.Subject | printf "%q"}}}`,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook returned error: %v", err)
+	}
+
+	if err := w.NotifyChange(context.Background(), config.Watch{Name: "my-watch"}, "Detected change", "text", "html", "diff"); err != nil {
+		t.Fatalf("NotifyChange returned error: %v", err)
+	}
+
+	want := `{"channel":"my-watch","text":"Detected change"}`
+	if string(gotBody) != want {
+		t.Errorf("templated webhook body = %q, want %q", gotBody, want)
+	}
+}
+
+func TestNewWebhookInvalidTemplate(t *testing.T) {
+	if _, err := NewWebhook(config.WebhookConfig{Name: "bad", Body: "{{.Nope"}); err == nil {
+		t.Error("NewWebhook with an invalid template should return an error")
+	}
+}
@@ -7,36 +7,119 @@ import (
 	"flag"
 	"fmt"
 	"html"
+	"log/slog"
 	"net/url"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/trhacknon/websitewatcher/internal/browser"
 	"github.com/trhacknon/websitewatcher/internal/config"
 	"github.com/trhacknon/websitewatcher/internal/database"
 	"github.com/trhacknon/websitewatcher/internal/diff"
 	"github.com/trhacknon/websitewatcher/internal/http"
+	"github.com/trhacknon/websitewatcher/internal/logger"
 	"github.com/trhacknon/websitewatcher/internal/mail"
+	"github.com/trhacknon/websitewatcher/internal/notify"
 	"golang.org/x/sync/semaphore"
-
-	"github.com/sirupsen/logrus"
 )
 
+// defaultFetcherName is used for watches that don't set config.Watch.Fetcher.
+const defaultFetcherName = "http"
+
 type app struct {
-	log        *logrus.Logger
+	log        logger.Logger
+	level      *logger.LevelVar
 	config     *config.Configuration
 	httpClient *http.HTTPClient
 	mailer     *mail.Mail
+	notifiers  notify.Notifiers
+	fetchers   map[string]http.Fetcher
 	testMode   bool
 	db         *database.Database
 }
 
+// watchOutcome is what happened when processing a single watch, used to
+// build the run summary event logged at the end of every invocation.
+type watchOutcome int
+
+const (
+	outcomeUnchanged watchOutcome = iota
+	outcomeChanged
+	outcomeSkipped
+	outcomeErrored
+)
+
+// fetcherForWatch resolves which Fetcher a watch should use, falling back to
+// the default "http" fetcher for an unset or unknown name. An unknown,
+// non-empty name is logged so a misspelled watch.Fetcher doesn't silently
+// fall back without a trace.
+func (app *app) fetcherForWatch(watch config.Watch, log logger.Logger) http.Fetcher {
+	name := watch.Fetcher
+	if name == "" {
+		name = defaultFetcherName
+	}
+	if fetcher, ok := app.fetchers[name]; ok {
+		return fetcher
+	}
+	if watch.Fetcher != "" {
+		log.Error("unknown fetcher, falling back to default", "fetcher", watch.Fetcher, "default", defaultFetcherName)
+	}
+	return app.fetchers[defaultFetcherName]
+}
+
+// buildNotifiers assembles every configured notifier, keyed by name, so
+// watches can fan a single change out to several of them. Names must be
+// unique: a configured ntfy/webhook reusing a built-in name (e.g. "mail")
+// would otherwise silently replace it for every watch defaulting to it.
+func buildNotifiers(mailer *mail.Mail, configuration *config.Configuration, testMode bool) (notify.Notifiers, error) {
+	notifiers := notify.Notifiers{
+		mailer.Name(): mailer,
+	}
+
+	addNotifier := func(n notify.Notifier) error {
+		if _, exists := notifiers[n.Name()]; exists {
+			return fmt.Errorf("notifier name %q is already in use, choose a different name", n.Name())
+		}
+		notifiers[n.Name()] = n
+		return nil
+	}
+
+	for _, c := range configuration.Ntfy {
+		if err := addNotifier(notify.NewNtfy(c)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range configuration.Webhooks {
+		w, err := notify.NewWebhook(c)
+		if err != nil {
+			return nil, fmt.Errorf("could not set up webhook %s: %w", c.Name, err)
+		}
+		if err := addNotifier(w); err != nil {
+			return nil, err
+		}
+	}
+
+	if testMode {
+		if err := addNotifier(notify.NewStdout(os.Stdout)); err != nil {
+			return nil, err
+		}
+	}
+
+	return notifiers, nil
+}
+
 func main() {
-	log := logrus.New()
+	level := &logger.LevelVar{}
 	app := app{
-		log: log,
+		log:   logger.New(level),
+		level: level,
 	}
 	if err := app.run(); err != nil {
 		app.logError(err)
@@ -45,22 +128,89 @@ func main() {
 }
 
 func (app *app) logError(err error) {
-	app.log.Errorf("[ERROR] %v", err)
+	app.log.Error(err.Error())
 }
 
-func (app *app) generateHTMLContentForEmail(body string, includeDiff bool, text1, text2 string) (string, error) {
-	body = strings.ReplaceAll(body, "\n", "<br>\n")
+func wrapHTMLBody(body string) string {
+	return fmt.Sprintf("<html><body>%s</body></html>", strings.ReplaceAll(body, "\n", "<br>\n"))
+}
 
-	if includeDiff {
-		css, html, err := diff.DiffAPI(app.httpClient, text1, text2)
-		if err != nil {
-			return "", err
-		}
-		body = fmt.Sprintf("<html><head><style>%s</style></head><body>%s<br><br>\n%s</body></html>", css, body, html)
+// renderDiff builds the notification content for a detected change: an HTML
+// view (using watch.Diff.Backend, local by default) to embed in the mail
+// body, and a plain representation (unified text, or JSON if configured)
+// for text-only notifiers such as ntfy and webhooks.
+func (app *app) renderDiff(watch config.Watch, body string, oldContent, newContent string) (htmlBody, plainDiff string, err error) {
+	oldContent = diff.Prepare(oldContent, watch.Diff.StripHTML, watch.Diff.NormalizeWhitespace)
+	newContent = diff.Prepare(newContent, watch.Diff.StripHTML, watch.Diff.NormalizeWhitespace)
+
+	var css, diffHTML string
+	if watch.Diff.Backend == "api" {
+		css, diffHTML, err = diff.DiffAPI(app.httpClient, oldContent, newContent)
 	} else {
-		body = fmt.Sprintf("<html><body>%s</body></html>", body)
+		css, diffHTML, err = diff.DiffHTML(oldContent, newContent)
 	}
-	return body, nil
+	if err != nil {
+		return "", "", err
+	}
+	htmlBody = fmt.Sprintf("<html><head><style>%s</style></head><body>%s<br><br>\n%s</body></html>", css, strings.ReplaceAll(body, "\n", "<br>\n"), diffHTML)
+
+	for _, format := range watch.Diff.Formats {
+		if format == "json" {
+			b, err := diff.DiffJSON(oldContent, newContent)
+			if err != nil {
+				return "", "", err
+			}
+			return htmlBody, string(b), nil
+		}
+	}
+
+	return htmlBody, diff.DiffText(oldContent, newContent), nil
+}
+
+// notifiersForWatch resolves which notifiers a watch should use. In test
+// mode everything is redirected to the stdout notifier so a dry run stays
+// visible without touching any real backend.
+func (app *app) notifiersForWatch(watch config.Watch) ([]notify.Notifier, error) {
+	if app.testMode {
+		return []notify.Notifier{app.notifiers["stdout"]}, nil
+	}
+	return app.notifiers.For(watch, app.config.DefaultNotifiers)
+}
+
+func (app *app) notifyError(ctx context.Context, watch config.Watch, watchErr error) error {
+	notifiers, err := app.notifiersForWatch(watch)
+	if err != nil {
+		return err
+	}
+	for _, n := range notifiers {
+		if err := n.NotifyError(ctx, watch, watchErr); err != nil {
+			return fmt.Errorf("could not notify %s via %s: %w", watch.Name, n.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (app *app) notifyChange(ctx context.Context, watch config.Watch, subject, textBody, htmlBody, diff string) error {
+	notifiers, err := app.notifiersForWatch(watch)
+	if err != nil {
+		return err
+	}
+	for _, n := range notifiers {
+		if err := n.NotifyChange(ctx, watch, subject, textBody, htmlBody, diff); err != nil {
+			return fmt.Errorf("could not notify %s via %s: %w", watch.Name, n.Name(), err)
+		}
+	}
+	return nil
+}
+
+// hostOf extracts the host component of rawURL, reporting false if it does
+// not parse, so the caller can pre-filter watches against BlockedHosts.
+func hostOf(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	return u.Host, true
 }
 
 func formatHeaders(header map[string][]string) string {
@@ -71,23 +221,52 @@ func formatHeaders(header map[string][]string) string {
 	return sb.String()
 }
 
+// applyLogLevel sets the hot-reloadable log level from debug flags/config.
+func (app *app) applyLogLevel(debugFlag bool, configuration *config.Configuration) {
+	if debugFlag || configuration.Debug {
+		app.level.Set(slog.LevelDebug)
+	} else {
+		app.level.Set(slog.LevelInfo)
+	}
+}
+
+// watchSIGHUP re-reads configFile on SIGHUP and hot-swaps the log level
+// without restarting, so a run already in progress can be flipped to debug
+// to diagnose a specific watch. It runs until ctx is done.
+func (app *app) watchSIGHUP(ctx context.Context, configFile string, debugFlag bool) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			reloaded, err := config.GetConfig(configFile)
+			if err != nil {
+				app.log.Error("could not reload config on SIGHUP", "error", err)
+				continue
+			}
+			app.applyLogLevel(debugFlag, reloaded)
+			app.log.Info("reloaded log level from config on SIGHUP", "debug", reloaded.Debug || debugFlag)
+		}
+	}
+}
+
 func (app *app) run() error {
 	configFile := flag.String("config", "", "config file to use")
 	debug := flag.Bool("debug", false, "Print debug output")
 	testMode := flag.Bool("test", false, "use test mode (no email sending)")
 	flag.Parse()
 
-	app.log.SetOutput(os.Stdout)
-	app.log.SetLevel(logrus.InfoLevel)
-	if *debug {
-		app.log.SetLevel(logrus.DebugLevel)
-	}
-
 	configuration, err := config.GetConfig(*configFile)
 	if err != nil {
 		return err
 	}
 
+	app.applyLogLevel(*debug, configuration)
+
 	start := time.Now().UnixNano()
 	db, err := database.ReadDatabase(configuration.Database)
 	if err != nil {
@@ -97,25 +276,50 @@ func (app *app) run() error {
 	// remove old websites in the database on each run
 	db.CleanupDatabase(app.log, *configuration)
 
-	httpClient := http.NewHTTPClient(configuration.Useragent, configuration.Retries, configuration.RetryDelay.Duration, configuration.Timeout.Duration, app.log)
+	httpClient := http.NewHTTPClient(configuration.Useragent, configuration.Retries, configuration.RetryDelay.Duration, configuration.MaxRetryDelay.Duration, configuration.Timeout.Duration, configuration.ErrorRateWindow.Duration, configuration.ErrorRateThreshold, configuration.HostCooldown.Duration)
 	mailer := mail.NewMail(configuration)
+	notifiers, err := buildNotifiers(mailer, configuration, *testMode)
+	if err != nil {
+		return err
+	}
 
 	app.config = configuration
 	app.httpClient = httpClient
 	app.testMode = *testMode
 	app.db = db
 	app.mailer = mailer
+	app.notifiers = notifiers
+	app.fetchers = map[string]http.Fetcher{
+		defaultFetcherName: httpClient,
+		"browser":          browser.NewFetcher(configuration.Timeout.Duration),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	ctx := context.Background()
+	go app.watchSIGHUP(ctx, *configFile, *debug)
+
+	var checked, changed, errored, skipped int64
 
 	var wg sync.WaitGroup
 	sem := semaphore.NewWeighted(configuration.ParallelChecks)
 	for _, watch := range configuration.Watches {
 		if watch.Disabled {
-			app.log.Infof("skipping %s: %s", watch.Name, watch.URL)
+			app.log.Info("skipping disabled watch", "watch", watch.Name, "url", watch.URL)
+			atomic.AddInt64(&skipped, 1)
 			continue
 		}
 
+		// pre-filter against hosts a prior watch in this same run already
+		// put into cooldown, so we skip before even taking a semaphore slot
+		if host, ok := hostOf(watch.URL); ok {
+			if retryAfter, blocked := httpClient.BlockedHosts()[host]; blocked {
+				app.log.Info("skipping watch, host is in cooldown", "watch", watch.Name, "url", watch.URL, "retry_after", retryAfter)
+				atomic.AddInt64(&skipped, 1)
+				continue
+			}
+		}
+
 		if err := sem.Acquire(ctx, 1); err != nil {
 			app.logError(err)
 			continue
@@ -126,19 +330,39 @@ func (app *app) run() error {
 			defer sem.Release(1)
 			defer wg.Done()
 
-			if err := app.processWatch(ctx, watch); err != nil {
+			atomic.AddInt64(&checked, 1)
+
+			outcome, err := app.processWatch(ctx, watch)
+			if err != nil {
+				atomic.AddInt64(&errored, 1)
 				app.logError(fmt.Errorf("error on %s: %w", watch.Name, err))
-				if err2 := app.mailer.SendErrorEmail(watch, err); err2 != nil {
+				if err2 := app.notifyError(ctx, watch, err); err2 != nil {
 					app.logError(err2)
-					return
 				}
 				return
 			}
+
+			switch outcome {
+			case outcomeChanged:
+				atomic.AddInt64(&changed, 1)
+			case outcomeSkipped:
+				atomic.AddInt64(&skipped, 1)
+			case outcomeErrored:
+				atomic.AddInt64(&errored, 1)
+			case outcomeUnchanged:
+			}
 		}(watch)
 	}
 
 	wg.Wait()
 
+	app.log.Info("run summary",
+		"checked", atomic.LoadInt64(&checked),
+		"changed", atomic.LoadInt64(&changed),
+		"errored", atomic.LoadInt64(&errored),
+		"skipped", atomic.LoadInt64(&skipped),
+	)
+
 	db.SetLastRun(start)
 	err = db.SaveDatabase(configuration.Database)
 	if err != nil {
@@ -148,49 +372,54 @@ func (app *app) run() error {
 	return nil
 }
 
-func (app *app) processWatch(ctx context.Context, watch config.Watch) error {
-	app.log.Infof("processing %s: %s", watch.Name, watch.URL)
+func (app *app) processWatch(ctx context.Context, watch config.Watch) (watchOutcome, error) {
+	log := app.log.With("watch", watch.Name, "url", watch.URL)
+	log.Info("processing watch")
 	lastContent := app.db.GetDatabaseEntry(watch.URL)
 
-	statusCode, _, requestDuration, body, err := app.httpClient.GetRequest(ctx, watch.URL)
+	statusCode, _, requestDuration, body, err := app.fetcherForWatch(watch, log).Fetch(ctx, watch, log)
 	if err != nil {
 		var invalidErr *http.InvalidResponseError
 		var urlErr *url.Error
+		var quotaErr *http.QuotaExceededError
 		switch {
+		case errors.As(err, &quotaErr):
+			// host is in a quota/error-rate cooldown, skip silently so we
+			// don't spam an error email on every scheduled run until it lifts
+			log.Info("skipping watch, host is in cooldown", "retry_after", quotaErr.RetryAfter)
+			return outcomeSkipped, nil
 		case errors.As(err, &invalidErr):
-			app.logError(fmt.Errorf("invalid response for %s - status: %d, body: %s, duration: %s", watch.Name, invalidErr.StatusCode, string(invalidErr.Body), requestDuration))
+			log.Error("invalid response", "status", invalidErr.StatusCode, "body", string(invalidErr.Body), "duration", requestDuration)
 
 			for _, ignore := range app.config.HTTPErrorsToIgnore {
 				if invalidErr.StatusCode == ignore {
 					// status is ignored, bail out
-					return nil
+					return outcomeSkipped, nil
 				}
 			}
 
 			for _, ignore := range watch.AdditionalHTTPErrorsToIgnore {
 				if invalidErr.StatusCode == ignore {
 					// status is ignored, bail out
-					return nil
+					return outcomeSkipped, nil
 				}
 			}
 
 			// send mail to indicate we might have an error
 			subject := fmt.Sprintf("Invalid response for %s", watch.Name)
 			text := fmt.Sprintf("Name: %s\nURL: %s\nRequest Duration: %s\nStatus: %d\nBodylen: %d\nHeader:\n%s\nBody:\n%s", watch.Name, watch.URL, requestDuration.Round(time.Millisecond), invalidErr.StatusCode, len(invalidErr.Body), html.EscapeString(formatHeaders(invalidErr.Header)), html.EscapeString(string(invalidErr.Body)))
-			htmlContent, err := app.generateHTMLContentForEmail(text, false, "", "")
-			if err != nil {
-				return fmt.Errorf("error on creating htmlcontent: %w", err)
-			}
-			if err := app.mailer.SendHTMLEmail(watch, subject, htmlContent); err != nil {
-				return fmt.Errorf("error on sending email: %w", err)
+			htmlContent := wrapHTMLBody(text)
+			if err := app.notifyChange(ctx, watch, subject, text, htmlContent, ""); err != nil {
+				return outcomeErrored, fmt.Errorf("error on sending notification: %w", err)
 			}
-			return nil
+			return outcomeErrored, nil
 		case errors.As(err, &urlErr) && urlErr.Timeout():
 			// ignore timeout errors so outer mail will not send emails on them
-			return nil
+			log.Info("skipping watch, request timed out")
+			return outcomeSkipped, nil
 		default:
 			// no custom handled error, return it so outer loop can handle it
-			return err
+			return outcomeErrored, err
 		}
 	}
 
@@ -198,52 +427,49 @@ func (app *app) processWatch(ctx context.Context, watch config.Watch) error {
 	if watch.Pattern != "" {
 		re, err := regexp.Compile(watch.Pattern)
 		if err != nil {
-			return fmt.Errorf("could not compile pattern %s: %w", watch.Pattern, err)
+			return outcomeErrored, fmt.Errorf("could not compile pattern %s: %w", watch.Pattern, err)
 		}
 		match := re.FindSubmatch(body)
 		if len(match) < 2 {
-			return fmt.Errorf("pattern %s did not match %s", watch.Pattern, string(body))
+			return outcomeErrored, fmt.Errorf("pattern %s did not match %s", watch.Pattern, string(body))
 		}
 		body = match[1]
 	}
 
 	for _, replace := range watch.Replaces {
-		app.log.Debugf("replacing %s", replace.Pattern)
+		log.Debug("applying replace", "pattern", replace.Pattern)
 		re, err := regexp.Compile(replace.Pattern)
 		if err != nil {
-			return fmt.Errorf("could not compile replace pattern %s: %w", replace.Pattern, err)
+			return outcomeErrored, fmt.Errorf("could not compile replace pattern %s: %w", replace.Pattern, err)
 		}
 		body = re.ReplaceAll(body, []byte(replace.ReplaceWith))
-		app.log.Debugf("After %s:\n%s\n\n", replace.Pattern, string(body))
 	}
 
 	// if it's a new website not yet in the database only process new entries and ignore old ones
 	if lastContent == nil {
 		// lastContent = nil on new sites not yet processed, so send no email here
-		app.log.Debugf("new website %s %s detected, not comparing", watch.Name, watch.URL)
+		log.Debug("new website detected, not comparing")
 		app.db.SetDatabaseEntry(watch.URL, body)
-		return nil
+		return outcomeUnchanged, nil
 	}
 
+	outcome := outcomeUnchanged
 	if !bytes.Equal(lastContent, body) {
-		if app.testMode {
-			app.log.Debugf("Website %s %s differ! Would send email in prod", watch.Name, watch.URL)
-		} else {
-			subject := fmt.Sprintf("Detected change on %s", watch.Name)
-			app.log.Infof(subject)
-			text := fmt.Sprintf("Name: %s\nURL: %s\nRequest Duration: %s\nStatus: %d\nBodylen: %d", watch.Name, watch.URL, requestDuration.Round(time.Millisecond), statusCode, len(body))
-			htmlContent, err := app.generateHTMLContentForEmail(text, true, string(lastContent), string(body))
-			if err != nil {
-				return fmt.Errorf("error on creating htmlcontent: %w", err)
-			}
-			if err := app.mailer.SendHTMLEmail(watch, subject, htmlContent); err != nil {
-				return fmt.Errorf("error on sending email: %w", err)
-			}
+		subject := fmt.Sprintf("Detected change on %s", watch.Name)
+		log.Info(subject)
+		text := fmt.Sprintf("Name: %s\nURL: %s\nRequest Duration: %s\nStatus: %d\nBodylen: %d", watch.Name, watch.URL, requestDuration.Round(time.Millisecond), statusCode, len(body))
+		htmlContent, plainDiff, err := app.renderDiff(watch, text, string(lastContent), string(body))
+		if err != nil {
+			return outcomeErrored, fmt.Errorf("error on creating diff: %w", err)
+		}
+		if err := app.notifyChange(ctx, watch, subject, text, htmlContent, plainDiff); err != nil {
+			return outcomeErrored, fmt.Errorf("error on sending notification: %w", err)
 		}
+		outcome = outcomeChanged
 	}
 
 	// update database entry if we did not have any errors
 	app.db.SetDatabaseEntry(watch.URL, body)
 
-	return nil
+	return outcome, nil
 }
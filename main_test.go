@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/trhacknon/websitewatcher/internal/config"
+	"github.com/trhacknon/websitewatcher/internal/mail"
+)
+
+func TestBuildNotifiersRejectsNameCollision(t *testing.T) {
+	mailer := mail.NewMail(&config.Configuration{})
+
+	tests := []struct {
+		name          string
+		configuration *config.Configuration
+		wantErr       bool
+	}{
+		{
+			name:          "distinct names are accepted",
+			configuration: &config.Configuration{Ntfy: []config.NtfyConfig{{Name: "ntfy"}}},
+		},
+		{
+			name:          "ntfy entry reusing the mail name is rejected",
+			configuration: &config.Configuration{Ntfy: []config.NtfyConfig{{Name: "mail"}}},
+			wantErr:       true,
+		},
+		{
+			name:          "webhook entry reusing the mail name is rejected",
+			configuration: &config.Configuration{Webhooks: []config.WebhookConfig{{Name: "mail"}}},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildNotifiers(mailer, tt.configuration, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildNotifiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}